@@ -0,0 +1,97 @@
+// Package config loads the optional, user-supplied coderefs.yaml file declaring custom flag reference
+// patterns and path skip rules.
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// DefaultPath is where Load looks for the config file when no override is given.
+const DefaultPath = ".launchdarkly/coderefs.yaml"
+
+// Rule is a single user-defined pattern used to find additional references to a flag, evaluated
+// alongside the standard delimiter and alias matching.
+type Rule struct {
+	// Name identifies the rule. It's attached to every hunk the rule matches, so that callers can tell
+	// which rule(s) produced a given reference.
+	Name string `yaml:"name"`
+	// Flags restricts the rule to specific flag keys. If empty, the rule is evaluated against every flag.
+	Flags []string `yaml:"flags,omitempty"`
+	// Pattern is a regular expression evaluated against each line of a file. The literal placeholder
+	// "{{flagKey}}" is replaced with the (regex-escaped) flag key before compiling, so a single rule can
+	// be reused across every flag, e.g. `Flags\.get\("{{flagKey}}"\)`.
+	Pattern string `yaml:"pattern"`
+}
+
+// Config is the user-supplied configuration loaded from a coderefs.yaml file.
+type Config struct {
+	Rules        []Rule   `yaml:"rules,omitempty"`
+	SkipPatterns []string `yaml:"skipPatterns,omitempty"`
+}
+
+// Load reads and parses the config file at path. A missing file isn't an error, since the file is
+// optional: Load returns an empty Config instead.
+func Load(path string) (*Config, error) {
+	contents, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &Config{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var c Config
+	if err := yaml.Unmarshal(contents, &c); err != nil {
+		return nil, fmt.Errorf("invalid config at %s: %w", path, err)
+	}
+	return &c, nil
+}
+
+// CompiledRule is a Rule whose pattern has been compiled for a specific flag key.
+type CompiledRule struct {
+	Name    string
+	Pattern *regexp.Regexp
+}
+
+// RulesForFlag compiles the rules that apply to flagKey.
+func (c *Config) RulesForFlag(flagKey string) ([]CompiledRule, error) {
+	compiled := make([]CompiledRule, 0, len(c.Rules))
+	for _, rule := range c.Rules {
+		if len(rule.Flags) > 0 && !contains(rule.Flags, flagKey) {
+			continue
+		}
+		pattern := strings.ReplaceAll(rule.Pattern, "{{flagKey}}", regexp.QuoteMeta(flagKey))
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("rule %q: %w", rule.Name, err)
+		}
+		compiled = append(compiled, CompiledRule{Name: rule.Name, Pattern: re})
+	}
+	return compiled, nil
+}
+
+// ShouldSkipPath reports whether path matches one of the configured skip patterns, and so should never
+// be enqueued for scanning.
+func (c *Config) ShouldSkipPath(path string) bool {
+	for _, pattern := range c.SkipPatterns {
+		if ok, _ := filepath.Match(pattern, path); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(s []string, v string) bool {
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
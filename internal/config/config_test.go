@@ -0,0 +1,105 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadMissingFileReturnsEmptyConfig(t *testing.T) {
+	cfg, err := Load(filepath.Join(t.TempDir(), "missing.yaml"))
+	if err != nil {
+		t.Fatalf("Load returned error for a missing file: %s", err)
+	}
+	if len(cfg.Rules) != 0 || len(cfg.SkipPatterns) != 0 {
+		t.Fatalf("expected an empty config, got %+v", cfg)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "coderefs.yaml")
+	contents := `
+rules:
+  - name: flag-helper
+    pattern: 'Flags\.get\("{{flagKey}}"\)'
+  - name: flag-specific
+    flags: ["my-flag"]
+    pattern: "myFlagOnly"
+skipPatterns:
+  - "vendor/*"
+`
+	if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load returned error: %s", err)
+	}
+	if len(cfg.Rules) != 2 || len(cfg.SkipPatterns) != 1 {
+		t.Fatalf("unexpected config: %+v", cfg)
+	}
+}
+
+func TestLoadInvalidYaml(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "coderefs.yaml")
+	if err := os.WriteFile(path, []byte("rules: [this is not valid"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Fatal("expected an error for invalid YAML")
+	}
+}
+
+func TestRulesForFlag(t *testing.T) {
+	cfg := &Config{
+		Rules: []Rule{
+			{Name: "global", Pattern: `Flags\.get\("{{flagKey}}"\)`},
+			{Name: "scoped", Flags: []string{"other-flag"}, Pattern: "anything"},
+		},
+	}
+
+	rules, err := cfg.RulesForFlag("my-flag")
+	if err != nil {
+		t.Fatalf("RulesForFlag returned error: %s", err)
+	}
+	if len(rules) != 1 || rules[0].Name != "global" {
+		t.Fatalf("expected only the global rule to apply, got %+v", rules)
+	}
+	if !rules[0].Pattern.MatchString(`Flags.get("my-flag")`) {
+		t.Fatalf("expected compiled pattern to match a reference to my-flag")
+	}
+
+	rules, err = cfg.RulesForFlag("other-flag")
+	if err != nil {
+		t.Fatalf("RulesForFlag returned error: %s", err)
+	}
+	if len(rules) != 2 {
+		t.Fatalf("expected both rules to apply to other-flag, got %+v", rules)
+	}
+}
+
+func TestRulesForFlagInvalidPattern(t *testing.T) {
+	cfg := &Config{Rules: []Rule{{Name: "bad", Pattern: "("}}}
+	if _, err := cfg.RulesForFlag("my-flag"); err == nil {
+		t.Fatal("expected an error for an invalid regex pattern")
+	}
+}
+
+func TestShouldSkipPath(t *testing.T) {
+	cfg := &Config{SkipPatterns: []string{"*.min.js", "vendor/*"}}
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"app.min.js", true},
+		{"vendor/lib.go", true},
+		{"main.go", false},
+	}
+	for _, tt := range tests {
+		if got := cfg.ShouldSkipPath(tt.path); got != tt.want {
+			t.Errorf("ShouldSkipPath(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
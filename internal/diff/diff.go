@@ -0,0 +1,104 @@
+// Package diff parses unified git diffs, as produced by `git diff` or embedded in a GitHub pull request
+// event payload.
+package diff
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// LineType identifies whether a diff line was added, removed, or present in both revisions.
+type LineType int
+
+const (
+	Context LineType = iota
+	Added
+	Removed
+)
+
+// Line is a single line of a diff hunk, tagged with its line number in whichever revision it belongs
+// to: the post-image for Added and Context lines, the pre-image for Removed lines.
+type Line struct {
+	Type   LineType
+	Text   string
+	Number int
+}
+
+// Hunk is a single `@@ ... @@` block within a file's diff.
+type Hunk struct {
+	Lines []Line
+}
+
+// FileDiff is the set of hunks found for a single file path in a diff.
+type FileDiff struct {
+	Path  string
+	Hunks []Hunk
+}
+
+var hunkHeaderPattern = regexp.MustCompile(`^@@ -(\d+)(?:,(?:\d+))? \+(\d+)(?:,(?:\d+))? @@`)
+var fileHeaderPattern = regexp.MustCompile(`^\+\+\+ (?:b/)?(.+)$`)
+
+// Parse reads a unified diff and returns the set of per-file hunks it contains. Deleted files (where
+// the post-image path is /dev/null) are skipped, since there is nothing to scan for added references;
+// a renamed file is kept under its post-image path.
+func Parse(r io.Reader) ([]FileDiff, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var files []FileDiff
+	var curFile *FileDiff
+	var curHunk *Hunk
+	var oldLineNum, newLineNum int
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "--- "):
+			// The pre-image file header. It carries no information Parse needs, but it must end any
+			// hunk still open from a previous file, or its own "-" prefix gets misread as that hunk's
+			// next removed line.
+			curHunk = nil
+		case strings.HasPrefix(line, "+++ "):
+			curHunk = nil
+			m := fileHeaderPattern.FindStringSubmatch(line)
+			if m == nil || m[1] == "/dev/null" {
+				curFile = nil
+				continue
+			}
+			files = append(files, FileDiff{Path: m[1]})
+			curFile = &files[len(files)-1]
+		case strings.HasPrefix(line, "@@ "):
+			if curFile == nil {
+				continue
+			}
+			m := hunkHeaderPattern.FindStringSubmatch(line)
+			if m == nil {
+				return nil, fmt.Errorf("malformed hunk header: %s", line)
+			}
+			oldLineNum, _ = strconv.Atoi(m[1])
+			newLineNum, _ = strconv.Atoi(m[2])
+			curFile.Hunks = append(curFile.Hunks, Hunk{})
+			curHunk = &curFile.Hunks[len(curFile.Hunks)-1]
+		case curHunk == nil:
+			continue
+		case strings.HasPrefix(line, "+"):
+			curHunk.Lines = append(curHunk.Lines, Line{Type: Added, Text: line[1:], Number: newLineNum})
+			newLineNum++
+		case strings.HasPrefix(line, "-"):
+			curHunk.Lines = append(curHunk.Lines, Line{Type: Removed, Text: line[1:], Number: oldLineNum})
+			oldLineNum++
+		case strings.HasPrefix(line, " "):
+			curHunk.Lines = append(curHunk.Lines, Line{Type: Context, Text: line[1:], Number: newLineNum})
+			oldLineNum++
+			newLineNum++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
@@ -0,0 +1,65 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+)
+
+const samplePatch = `diff --git a/foo.go b/foo.go
+index 1111111..2222222 100644
+--- a/foo.go
++++ b/foo.go
+@@ -10,3 +10,4 @@ func foo() {
+ context line
+-removed line
++added line 1
++added line 2
+diff --git a/deleted.go b/deleted.go
+deleted file mode 100644
+--- a/deleted.go
++++ /dev/null
+@@ -1,2 +0,0 @@
+-gone
+-also gone
+`
+
+func TestParse(t *testing.T) {
+	files, err := Parse(strings.NewReader(samplePatch))
+	if err != nil {
+		t.Fatalf("Parse returned error: %s", err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("expected 1 file (deleted file should be skipped), got %d: %+v", len(files), files)
+	}
+
+	fd := files[0]
+	if fd.Path != "foo.go" {
+		t.Fatalf("expected path foo.go, got %s", fd.Path)
+	}
+	if len(fd.Hunks) != 1 {
+		t.Fatalf("expected 1 hunk, got %d", len(fd.Hunks))
+	}
+
+	wantLines := []Line{
+		{Type: Context, Text: "context line", Number: 10},
+		{Type: Removed, Text: "removed line", Number: 11},
+		{Type: Added, Text: "added line 1", Number: 11},
+		{Type: Added, Text: "added line 2", Number: 12},
+	}
+	gotLines := fd.Hunks[0].Lines
+	if len(gotLines) != len(wantLines) {
+		t.Fatalf("expected %d lines, got %d: %+v", len(wantLines), len(gotLines), gotLines)
+	}
+	for i, want := range wantLines {
+		if gotLines[i] != want {
+			t.Errorf("line %d: expected %+v, got %+v", i, want, gotLines[i])
+		}
+	}
+}
+
+func TestParseMalformedHunkHeader(t *testing.T) {
+	patch := "--- a/foo.go\n+++ b/foo.go\n@@ not a header @@\n"
+	if _, err := Parse(strings.NewReader(patch)); err == nil {
+		t.Fatal("expected an error for a malformed hunk header")
+	}
+}
@@ -0,0 +1,16 @@
+// Package helpers contains small generic utilities shared across the rest of the program.
+package helpers
+
+// Dedupe returns a new slice containing the unique, non-empty elements of s, preserving their original order.
+func Dedupe(s []string) []string {
+	seen := make(map[string]bool, len(s))
+	ret := make([]string, 0, len(s))
+	for _, v := range s {
+		if v == "" || seen[v] {
+			continue
+		}
+		seen[v] = true
+		ret = append(ret, v)
+	}
+	return ret
+}
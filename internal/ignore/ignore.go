@@ -0,0 +1,97 @@
+// Package ignore provides gitignore-aware path filtering, driven by .gitignore and the
+// coderefs-specific .ldignore.
+package ignore
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+)
+
+// ldIgnoreFile is read alongside .gitignore at every directory level, using the same pattern syntax, for
+// repos that want to exclude paths from code reference scanning without also excluding them from git.
+const ldIgnoreFile = ".ldignore"
+
+// Matcher reports whether a path relative to a workspace root should be skipped, based on the
+// .gitignore and .ldignore files found at every directory level of that workspace.
+type Matcher struct {
+	matcher gitignore.Matcher
+}
+
+// NewMatcher walks workspace collecting .gitignore and .ldignore patterns at every directory level and
+// returns a Matcher that tests individual paths against them. It never descends into .git, and it stops
+// descending into any directory already excluded by the patterns collected so far, so that a repo with a
+// large ignored subtree (vendor/, node_modules/, a build output directory) only pays the cost of
+// stat-ing that subtree's root rather than every file beneath it.
+func NewMatcher(workspace string) (*Matcher, error) {
+	var patterns []gitignore.Pattern
+	err := filepath.Walk(workspace, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(workspace, path)
+		if err != nil {
+			return err
+		}
+		if rel == ".git" {
+			return filepath.SkipDir
+		}
+
+		var domain []string
+		if rel != "." {
+			domain = strings.Split(rel, string(filepath.Separator))
+			if gitignore.NewMatcher(patterns).Match(domain, true) {
+				return filepath.SkipDir
+			}
+		}
+
+		for _, name := range []string{".gitignore", ldIgnoreFile} {
+			filePatterns, err := readPatterns(filepath.Join(path, name), domain)
+			if err != nil {
+				return err
+			}
+			patterns = append(patterns, filePatterns...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Matcher{matcher: gitignore.NewMatcher(patterns)}, nil
+}
+
+func readPatterns(path string, domain []string) ([]gitignore.Pattern, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var patterns []gitignore.Pattern
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if trimmed := strings.TrimSpace(line); trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+			patterns = append(patterns, gitignore.ParsePattern(line, domain))
+		}
+	}
+	return patterns, scanner.Err()
+}
+
+// Match reports whether path, relative to the workspace root passed to NewMatcher, should be excluded
+// from scanning. A nil Matcher never excludes anything.
+func (m *Matcher) Match(path string, isDir bool) bool {
+	if m == nil {
+		return false
+	}
+	return m.matcher.Match(strings.Split(path, string(filepath.Separator)), isDir)
+}
@@ -0,0 +1,66 @@
+package ignore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, contents string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewMatcher(t *testing.T) {
+	workspace := t.TempDir()
+	writeFile(t, filepath.Join(workspace, ".gitignore"), "vendor/\n*.log\n")
+	writeFile(t, filepath.Join(workspace, "sub", ".ldignore"), "generated.go\n")
+	writeFile(t, filepath.Join(workspace, "vendor", "lib.go"), "package lib")
+	writeFile(t, filepath.Join(workspace, "sub", "generated.go"), "package sub")
+	writeFile(t, filepath.Join(workspace, "sub", "real.go"), "package sub")
+
+	m, err := NewMatcher(workspace)
+	if err != nil {
+		t.Fatalf("NewMatcher returned error: %s", err)
+	}
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"vendor", true, true},
+		{"vendor/lib.go", false, true},
+		{"debug.log", false, true},
+		{"sub/generated.go", false, true},
+		{"sub/real.go", false, false},
+		{"main.go", false, false},
+	}
+	for _, tt := range tests {
+		if got := m.Match(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("Match(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+		}
+	}
+}
+
+func TestNewMatcherSkipsGitDirectory(t *testing.T) {
+	workspace := t.TempDir()
+	// A file inside .git that NewMatcher should never need to read or descend into.
+	writeFile(t, filepath.Join(workspace, ".git", "HEAD"), "ref: refs/heads/main")
+
+	if _, err := NewMatcher(workspace); err != nil {
+		t.Fatalf("NewMatcher returned error: %s", err)
+	}
+}
+
+func TestNilMatcherNeverExcludes(t *testing.T) {
+	var m *Matcher
+	if m.Match("anything", false) {
+		t.Fatal("a nil Matcher should never report a match")
+	}
+}
@@ -0,0 +1,127 @@
+package ld
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// flagsResponse is the subset of LaunchDarkly's list-flags response GetFlagKeys needs.
+type flagsResponse struct {
+	Items []struct {
+		Key string `json:"key"`
+	} `json:"items"`
+}
+
+// GetFlagKeys fetches the keys of every flag in projKey, for use as SearchForRefs' search terms.
+func (c *Client) GetFlagKeys(projKey string) ([]string, error) {
+	url := fmt.Sprintf("%s/api/v2/flags/%s?summary=true", c.BaseUri, projKey)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	c.setHeaders(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("GET %s returned status %d", url, resp.StatusCode)
+	}
+
+	var parsed flagsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(parsed.Items))
+	for _, item := range parsed.Items {
+		keys = append(keys, item.Key)
+	}
+	return keys, nil
+}
+
+// ReferenceBatch is a bounded-size slice of a branch's code reference hunks, uploaded as one chunk of a
+// sequenced, multi-part PUT rather than as a single, unbounded payload.
+type ReferenceBatch struct {
+	References []ReferenceHunksRep `json:"references"`
+}
+
+// Client makes authenticated requests to LaunchDarkly's code references API.
+type Client struct {
+	AccessToken string
+	BaseUri     string
+	UserAgent   string
+	httpClient  *http.Client
+}
+
+// NewClient returns a Client that authenticates with accessToken and sends requests to baseUri.
+func NewClient(accessToken, baseUri, userAgent string) *Client {
+	return &Client{AccessToken: accessToken, BaseUri: baseUri, UserAgent: userAgent, httpClient: http.DefaultClient}
+}
+
+// PutCodeReferenceBranchChunk uploads one chunk of a branch's code reference hunks. seq is this chunk's
+// 0-indexed position among the chunks that make up the branch's full reference set. Chunks can be
+// uploaded one at a time as they become available, in any order, and are staged server-side; they only
+// take effect once every chunk has been uploaded, and the backend has been told how many to expect, via
+// PutCodeReferenceBranchChunksComplete.
+func (c *Client) PutCodeReferenceBranchChunk(repoName, branchName string, batch ReferenceBatch, seq int) error {
+	body, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("%s/api/v2/code-refs/repositories/%s/branches/%s/references/chunks/%d", c.BaseUri, repoName, branchName, seq)
+	req, err := http.NewRequest(http.MethodPut, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	c.setHeaders(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("PUT %s returned status %d", url, resp.StatusCode)
+	}
+	return nil
+}
+
+// PutCodeReferenceBranchChunksComplete tells the backend that every chunk for a branch has been
+// uploaded, so it can atomically swap the branch's reference set to the newly-uploaded one rather than
+// partially applying an in-progress upload.
+func (c *Client) PutCodeReferenceBranchChunksComplete(repoName, branchName string, totalChunks int) error {
+	body, err := json.Marshal(map[string]int{"totalChunks": totalChunks})
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("%s/api/v2/code-refs/repositories/%s/branches/%s/references/chunks/complete", c.BaseUri, repoName, branchName)
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	c.setHeaders(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("POST %s returned status %d", url, resp.StatusCode)
+	}
+	return nil
+}
+
+func (c *Client) setHeaders(req *http.Request) {
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", c.AccessToken)
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+}
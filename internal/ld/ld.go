@@ -0,0 +1,48 @@
+// Package ld contains the types representing code reference data as it is sent to and received from
+// LaunchDarkly's code references API.
+package ld
+
+import "strings"
+
+// HunkRep represents a contiguous block of lines in a file containing one or more references to a flag.
+type HunkRep struct {
+	StartingLineNumber int      `json:"startingLineNumber"`
+	Lines              string   `json:"lines,omitempty"`
+	ProjKey            string   `json:"projKey"`
+	FlagKey            string   `json:"flagKey"`
+	Aliases            []string `json:"aliases,omitempty"`
+
+	// MatchedRules lists the names of any user-defined config rules (see package config) that matched
+	// this hunk, in addition to or instead of the standard delimiter/alias matching.
+	MatchedRules []string `json:"matchedRules,omitempty"`
+
+	// CommitHash, CommitAuthorEmail, and CommitTime identify the commit that last touched the line the
+	// hunk's match was found on. They are only populated when blame attribution has been requested for
+	// the scan.
+	CommitHash        string `json:"commitHash,omitempty"`
+	CommitAuthorEmail string `json:"commitAuthorEmail,omitempty"`
+	CommitTime        int64  `json:"commitTime,omitempty"`
+}
+
+// ReferenceHunksRep represents all of the code reference hunks found in a single file.
+type ReferenceHunksRep struct {
+	Path  string    `json:"path"`
+	Hunks []HunkRep `json:"hunks"`
+}
+
+// Overlap returns the number of lines by which h and o overlap. If the hunks do not overlap, Overlap
+// returns a negative number. Assumes h.StartingLineNumber <= o.StartingLineNumber.
+func (h HunkRep) Overlap(o HunkRep) int {
+	if h.Lines == "" {
+		return -1
+	}
+	hEnd := h.StartingLineNumber + len(strings.Split(h.Lines, "\n"))
+	return hEnd - o.StartingLineNumber
+}
+
+// ReferenceDeltaRep represents the code reference hunks added and removed by a single pull request or
+// commit range, as produced by a diff-only scan rather than a full workspace scan.
+type ReferenceDeltaRep struct {
+	Added   []HunkRep `json:"added"`
+	Removed []HunkRep `json:"removed"`
+}
@@ -0,0 +1,28 @@
+// Package log provides the program's two loggers: Info for normal progress output, and Error for
+// warnings and fatal errors. Debug-level detail on Info is gated behind Init's debug argument.
+package log
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+)
+
+var (
+	// Info logs normal progress output. It's silenced unless Init is called with debug set to true.
+	Info *log.Logger
+	// Error logs warnings and fatal errors. It always writes to stderr.
+	Error *log.Logger
+)
+
+// Init sets up Info and Error. debug controls whether Info actually writes anything; it's always
+// called before flags have necessarily finished parsing, since an early option-parsing error needs
+// somewhere to go.
+func Init(debug bool) {
+	infoOutput := ioutil.Discard
+	if debug {
+		infoOutput = os.Stdout
+	}
+	Info = log.New(infoOutput, "INFO: ", log.LstdFlags)
+	Error = log.New(os.Stderr, "ERROR: ", log.LstdFlags)
+}
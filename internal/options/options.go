@@ -0,0 +1,152 @@
+// Package options defines and parses the flags that configure a scan, whether they were set on the
+// command line or (as is the case when running as a CI job) via environment variables.
+package options
+
+import (
+	"flag"
+	"os"
+	"strconv"
+
+	"github.com/launchdarkly/ld-find-code-refs/internal/config"
+)
+
+// Core scan options. Most of these are populated by a pkg/hostenv provider rather than set directly by
+// the user, but they're ordinary flags so that hostenv (and, for local runs, the user) can set them with
+// flag.Set in the same way regardless of where the value came from.
+var accessToken = flag.String("accessToken", "", "LaunchDarkly personal access token with write access to the project's code references")
+var projKey = flag.String("projKey", "", "LaunchDarkly project key")
+var repoType = flag.String("repoType", "", "Type of repository host, e.g. github, gitlab, bitbucket, or custom")
+var repoName = flag.String("repoName", "", "Name LaunchDarkly should use to identify this repository")
+var repoUrl = flag.String("repoUrl", "", "URL LaunchDarkly should link to for this repository")
+var branch = flag.String("branch", "", "Branch being scanned")
+var defaultBranch = flag.String("defaultBranch", "", "The repository's default branch")
+var commitSha = flag.String("commitSha", "", "Commit SHA being scanned, if it differs from the branch's current HEAD")
+var dir = flag.String("dir", ".", "Path to the git workspace to scan")
+var updateSequenceId = flag.Int64("updateSequenceId", -1, "Monotonically increasing version for this update, used to ensure updates are applied in order. Defaults to the scanned commit's timestamp.")
+var ctxLines = flag.Int("ctxLines", 2, "Number of context lines above and below a code reference to send to LaunchDarkly")
+var delimiters = flag.String("delimiters", "\"'`", "Characters that may surround a flag key reference")
+
+var withBlame = flag.Bool("withBlame", false, "Enables git blame attribution for each code reference hunk. This increases scan time, since a blame must be computed for every file containing a match.")
+var configPath = flag.String("config", config.DefaultPath, "Path to a coderefs.yaml file declaring custom flag reference patterns and path skip rules")
+var minifiedLineLengthThreshold = flag.Int("minifiedLineLengthThreshold", 0, "Skip files whose average line length exceeds this many characters, as a cheap heuristic for minified or generated files. 0 disables this check.")
+
+var diffMode = flag.Bool("diffMode", false, "Scan a unified diff read from stdin instead of the full workspace, and report only the flag references it adds and removes rather than uploading a full reference set.")
+var baseUri = flag.String("baseUri", "https://app.launchdarkly.com", "LaunchDarkly base URI")
+
+// Populate parses the registered flags from the command line. It must be called before any option is
+// read; values set afterwards with flag.Set override what was parsed.
+func Populate() error {
+	if !flag.Parsed() {
+		flag.Parse()
+	}
+	return nil
+}
+
+// AccessToken returns the LaunchDarkly access token to use for this run.
+func AccessToken() string {
+	return *accessToken
+}
+
+// ProjKey returns the LaunchDarkly project key to use for this run.
+func ProjKey() string {
+	return *projKey
+}
+
+// RepoType returns the repository host type, e.g. "github" or "custom".
+func RepoType() string {
+	return *repoType
+}
+
+// RepoName returns the name LaunchDarkly should use to identify this repository.
+func RepoName() string {
+	return *repoName
+}
+
+// RepoUrl returns the URL LaunchDarkly should link to for this repository.
+func RepoUrl() string {
+	return *repoUrl
+}
+
+// Branch returns the branch being scanned.
+func Branch() string {
+	return *branch
+}
+
+// DefaultBranch returns the repository's default branch.
+func DefaultBranch() string {
+	return *defaultBranch
+}
+
+// CommitSha returns the commit SHA being scanned, or "" if it's just the branch's current HEAD.
+func CommitSha() string {
+	return *commitSha
+}
+
+// Dir returns the path to the git workspace to scan.
+func Dir() string {
+	return *dir
+}
+
+// UpdateSequenceId returns the monotonically increasing version to attach to this run's update, or -1 if
+// none was set.
+func UpdateSequenceId() int64 {
+	return *updateSequenceId
+}
+
+// CtxLines returns the number of context lines to send above and below each code reference.
+func CtxLines() int {
+	return *ctxLines
+}
+
+// Delimiters returns the characters that may surround a flag key reference.
+func Delimiters() string {
+	return *delimiters
+}
+
+// WithBlame reports whether git blame attribution has been requested for this run.
+func WithBlame() bool {
+	return *withBlame
+}
+
+// ConfigPath returns the path to the coderefs.yaml config file to load for this run.
+func ConfigPath() string {
+	return *configPath
+}
+
+// MinifiedLineLengthThreshold returns the average-line-length, in characters, above which a file is
+// assumed to be minified or generated and skipped outright. A value <= 0 means the check is disabled.
+func MinifiedLineLengthThreshold() int {
+	return *minifiedLineLengthThreshold
+}
+
+// DiffMode reports whether this run should scan a unified diff from stdin instead of the full workspace.
+func DiffMode() bool {
+	return *diffMode
+}
+
+// BaseUri returns the LaunchDarkly base URI to send code reference API requests to.
+func BaseUri() string {
+	return *baseUri
+}
+
+// GetDebugOptionFromEnv reads the LD_DEBUG environment variable, used to enable verbose logging before
+// the rest of the options have been parsed.
+func GetDebugOptionFromEnv() (bool, error) {
+	v, ok := os.LookupEnv("LD_DEBUG")
+	if !ok || v == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(v)
+}
+
+// GetLDOptionsFromEnv reads the subset of options that may be provided via LD_-prefixed environment
+// variables, e.g. for use in CI providers that don't support passing flags directly.
+func GetLDOptionsFromEnv() (map[string]string, error) {
+	options := map[string]string{}
+	for _, name := range []string{"accessToken", "projKey", "ctxLines", "delimiters"} {
+		if v, ok := os.LookupEnv("LD_" + name); ok {
+			options[name] = v
+		}
+	}
+	return options, nil
+}
@@ -0,0 +1,70 @@
+package search
+
+import (
+	"sync"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+
+	"github.com/launchdarkly/ld-find-code-refs/internal/ld"
+)
+
+// blameSource lazily computes and caches git blame results for the files of a single scan, so that a
+// given file is only ever blamed once no matter how many flags match lines within it.
+type blameSource struct {
+	headCommit *object.Commit
+
+	mu    sync.Mutex
+	cache map[string]*git.BlameResult
+}
+
+// newBlameSource opens workspace as a git repository and resolves its HEAD commit, which all blame
+// lookups for the scan are computed against.
+func newBlameSource(workspace string) (*blameSource, error) {
+	repo, err := git.PlainOpen(workspace)
+	if err != nil {
+		return nil, err
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return nil, err
+	}
+	commit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return nil, err
+	}
+	return &blameSource{headCommit: commit, cache: map[string]*git.BlameResult{}}, nil
+}
+
+func (b *blameSource) blameForFile(path string) (*git.BlameResult, error) {
+	b.mu.Lock()
+	if result, ok := b.cache[path]; ok {
+		b.mu.Unlock()
+		return result, nil
+	}
+	b.mu.Unlock()
+
+	result, err := git.Blame(b.headCommit, path)
+	if err != nil {
+		return nil, err
+	}
+
+	b.mu.Lock()
+	b.cache[path] = result
+	b.mu.Unlock()
+	return result, nil
+}
+
+// annotate populates hunk's commit attribution fields with the blame info for path's matchLineNum
+// (0-indexed). Failures to blame a file or line are swallowed, since attribution is a best-effort
+// enhancement and shouldn't cause an otherwise-valid match to be dropped.
+func (b *blameSource) annotate(hunk *ld.HunkRep, path string, matchLineNum int) {
+	result, err := b.blameForFile(path)
+	if err != nil || matchLineNum < 0 || matchLineNum >= len(result.Lines) {
+		return
+	}
+	line := result.Lines[matchLineNum]
+	hunk.CommitHash = line.Hash.String()
+	hunk.CommitAuthorEmail = line.Author
+	hunk.CommitTime = line.Date.Unix()
+}
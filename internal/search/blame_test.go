@@ -0,0 +1,124 @@
+package search
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+
+	"github.com/launchdarkly/ld-find-code-refs/internal/ld"
+)
+
+// initRepoWithTwoCommits creates a git repository at workspace with two commits to file: the first
+// writes firstContents, the second appends a line, so a test can assert that annotate attributes each
+// line to the commit that actually introduced it.
+func initRepoWithTwoCommits(t *testing.T, file, firstContents, secondContents string) string {
+	t.Helper()
+	workspace := t.TempDir()
+
+	repo, err := git.PlainInit(workspace, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(workspace, file)
+	if err := os.WriteFile(path, []byte(firstContents), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := worktree.Add(file); err != nil {
+		t.Fatal(err)
+	}
+	firstAuthor := &object.Signature{Name: "Alice", Email: "alice@example.com", When: time.Unix(1000, 0)}
+	if _, err := worktree.Commit("first", &git.CommitOptions{Author: firstAuthor}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(path, []byte(secondContents), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := worktree.Add(file); err != nil {
+		t.Fatal(err)
+	}
+	secondAuthor := &object.Signature{Name: "Bob", Email: "bob@example.com", When: time.Unix(2000, 0)}
+	if _, err := worktree.Commit("second", &git.CommitOptions{Author: secondAuthor}); err != nil {
+		t.Fatal(err)
+	}
+
+	return workspace
+}
+
+func TestAnnotate(t *testing.T) {
+	workspace := initRepoWithTwoCommits(t, "flags.go", "line one\n", "line one\nline two\n")
+
+	b, err := newBlameSource(workspace)
+	if err != nil {
+		t.Fatalf("newBlameSource returned error: %s", err)
+	}
+
+	var hunk ld.HunkRep
+	b.annotate(&hunk, "flags.go", 0)
+	if hunk.CommitAuthorEmail != "alice@example.com" {
+		t.Errorf("line 0: expected alice@example.com, got %s", hunk.CommitAuthorEmail)
+	}
+	if hunk.CommitTime != 1000 {
+		t.Errorf("line 0: expected commit time 1000, got %d", hunk.CommitTime)
+	}
+	if hunk.CommitHash == "" {
+		t.Error("line 0: expected a non-empty commit hash")
+	}
+
+	var hunk2 ld.HunkRep
+	b.annotate(&hunk2, "flags.go", 1)
+	if hunk2.CommitAuthorEmail != "bob@example.com" {
+		t.Errorf("line 1: expected bob@example.com, got %s", hunk2.CommitAuthorEmail)
+	}
+	if hunk2.CommitTime != 2000 {
+		t.Errorf("line 1: expected commit time 2000, got %d", hunk2.CommitTime)
+	}
+}
+
+func TestAnnotateOutOfRangeLineIsIgnored(t *testing.T) {
+	workspace := initRepoWithTwoCommits(t, "flags.go", "line one\n", "line one\nline two\n")
+
+	b, err := newBlameSource(workspace)
+	if err != nil {
+		t.Fatalf("newBlameSource returned error: %s", err)
+	}
+
+	var hunk ld.HunkRep
+	b.annotate(&hunk, "flags.go", 100)
+	if hunk.CommitHash != "" || hunk.CommitAuthorEmail != "" || hunk.CommitTime != 0 {
+		t.Errorf("expected an out-of-range line to leave hunk unannotated, got %+v", hunk)
+	}
+}
+
+// TestBlameForFileCacheIsConcurrencySafe exercises blameForFile's cache from many goroutines at once, for
+// -race to catch a regression in its mutex-guarded access.
+func TestBlameForFileCacheIsConcurrencySafe(t *testing.T) {
+	workspace := initRepoWithTwoCommits(t, "flags.go", "line one\n", "line one\nline two\n")
+
+	b, err := newBlameSource(workspace)
+	if err != nil {
+		t.Fatalf("newBlameSource returned error: %s", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := b.blameForFile("flags.go"); err != nil {
+				t.Errorf("blameForFile returned error: %s", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,77 @@
+package search
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/launchdarkly/ld-find-code-refs/internal/config"
+	"github.com/launchdarkly/ld-find-code-refs/internal/diff"
+)
+
+func TestSearchForRefsInDiff(t *testing.T) {
+	fileDiffs := []diff.FileDiff{
+		{
+			Path: "foo.go",
+			Hunks: []diff.Hunk{
+				{
+					Lines: []diff.Line{
+						{Type: diff.Context, Text: "before", Number: 1},
+						{Type: diff.Removed, Text: `flag := "old-flag"`, Number: 2},
+						{Type: diff.Added, Text: `flag := "new-flag"`, Number: 2},
+						{Type: diff.Context, Text: "after", Number: 3},
+					},
+				},
+			},
+		},
+	}
+	headLines := func(path string) ([]string, bool) {
+		if path != "foo.go" {
+			return nil, false
+		}
+		return []string{"before", `flag := "new-flag"`, "after"}, true
+	}
+	aliases := map[string][]string{"new-flag": nil, "old-flag": nil}
+
+	delta := SearchForRefsInDiff("proj", aliases, 1, "\"", fileDiffs, headLines, nil)
+
+	if len(delta.Added) != 1 || delta.Added[0].FlagKey != "new-flag" {
+		t.Fatalf("expected one added reference to new-flag, got %+v", delta.Added)
+	}
+	if len(delta.Removed) != 1 || delta.Removed[0].FlagKey != "old-flag" {
+		t.Fatalf("expected one removed reference to old-flag, got %+v", delta.Removed)
+	}
+}
+
+// TestSearchForRefsInDiffHonorsCustomRules verifies that a custom coderefs.yaml pattern, which wouldn't
+// otherwise match because the flag key never appears between delimiters, still produces a reference in diff
+// mode, matching full-scan behavior.
+func TestSearchForRefsInDiffHonorsCustomRules(t *testing.T) {
+	fileDiffs := []diff.FileDiff{
+		{
+			Path: "foo.go",
+			Hunks: []diff.Hunk{
+				{
+					Lines: []diff.Line{
+						{Type: diff.Added, Text: `getBoolVariation("my-flag-alias-only-a-rule-can-find")`, Number: 1},
+					},
+				},
+			},
+		},
+	}
+	headLines := func(path string) ([]string, bool) {
+		return []string{`getBoolVariation("my-flag-alias-only-a-rule-can-find")`}, true
+	}
+	aliases := map[string][]string{"my-flag": nil}
+	rules := map[string][]config.CompiledRule{
+		"my-flag": {{Name: "custom", Pattern: regexp.MustCompile(`getBoolVariation\(".*"\)`)}},
+	}
+
+	delta := SearchForRefsInDiff("proj", aliases, 0, "\"", fileDiffs, headLines, rules)
+
+	if len(delta.Added) != 1 || delta.Added[0].FlagKey != "my-flag" {
+		t.Fatalf("expected one added reference matched by the custom rule, got %+v", delta.Added)
+	}
+	if len(delta.Added[0].MatchedRules) != 1 || delta.Added[0].MatchedRules[0] != "custom" {
+		t.Fatalf("expected the match to record the custom rule name, got %+v", delta.Added[0].MatchedRules)
+	}
+}
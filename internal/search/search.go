@@ -1,22 +1,33 @@
 package search
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 
+	"github.com/launchdarkly/ld-find-code-refs/internal/config"
+	"github.com/launchdarkly/ld-find-code-refs/internal/diff"
 	"github.com/launchdarkly/ld-find-code-refs/internal/helpers"
+	"github.com/launchdarkly/ld-find-code-refs/internal/ignore"
 	"github.com/launchdarkly/ld-find-code-refs/internal/ld"
+	"github.com/launchdarkly/ld-find-code-refs/internal/options"
 )
 
 const (
-	// These are defensive limits intended to prevent corner cases stemming from
-	// large repos, false positives, etc. The goal is a) to prevent the program
-	// from taking a very long time to run and b) to prevent the program from
-	// PUTing a massive json payload. These limits will likely be tweaked over
-	// time. The LaunchDarkly backend will also apply limits.
-	maxFileCount     = 10000 // Maximum number of files containing code references
-	maxHunkCount     = 25000 // Maximum number of total code references
-	maxLineCharCount = 500   // Maximum number of characters per line
+	// recommendedMaxFileCount and recommendedMaxHunkCount are soft limits: a scan that exceeds them
+	// isn't truncated, but SearchForRefs reports it via ScanStats so the caller can warn rather than
+	// silently drop references, as happened when these were hard limits.
+	recommendedMaxFileCount = 10000 // Recommended maximum number of files containing code references
+	recommendedMaxHunkCount = 25000 // Recommended maximum number of total code references
+
+	maxLineCharCount = 500 // Maximum number of characters per line
+
+	// batchHunkSize is the approximate number of hunks SearchForRefs packs into each ReferenceBatch it
+	// streams to its caller, so that references can be uploaded incrementally instead of being held in
+	// memory (and in a single PUT payload) all at once.
+	batchHunkSize = 2000
 )
 
 // Truncate lines to prevent sending over massive hunks, e.g. a minified file.
@@ -33,6 +44,21 @@ func truncateLine(line string) string {
 	return string(runes[0:maxLineCharCount]) + "…"
 }
 
+// IsLikelyMinified reports whether lines looks like a minified or generated file, based on its average
+// line length exceeding threshold. readFiles checks it once a file has been read and before the file is
+// enqueued for scanning, so that truncateLine is a fallback for the occasional long line rather than the first line of
+// defense against minified bundles. A threshold <= 0 disables the check.
+func IsLikelyMinified(lines []string, threshold int) bool {
+	if threshold <= 0 || len(lines) == 0 {
+		return false
+	}
+	total := 0
+	for _, line := range lines {
+		total += len(line)
+	}
+	return total/len(lines) > threshold
+}
+
 func matchDelimiters(match string, flagKey string, delimiters string) bool {
 	for _, left := range delimiters {
 		for _, right := range delimiters {
@@ -49,9 +75,10 @@ type file struct {
 	lines []string
 }
 
-func (f file) linesIfMatch(projKey, flagKey, line string, aliases []string, matchLineNum, ctxLines int, delimiters string) *ld.HunkRep {
+func (f file) linesIfMatch(projKey, flagKey, line string, aliases []string, matchLineNum, ctxLines int, delimiters string, blame *blameSource, rules []config.CompiledRule) *ld.HunkRep {
 	matchedFlag := false
 	aliasMatches := []string{}
+	matchedRules := []string{}
 
 	// Match flag keys with delimiters
 	if matchDelimiters(line, flagKey, delimiters) {
@@ -65,7 +92,14 @@ func (f file) linesIfMatch(projKey, flagKey, line string, aliases []string, matc
 		}
 	}
 
-	if !matchedFlag && len(aliasMatches) == 0 {
+	// Match any user-defined rules for the flag key
+	for _, rule := range rules {
+		if rule.Pattern.MatchString(line) {
+			matchedRules = append(matchedRules, rule.Name)
+		}
+	}
+
+	if !matchedFlag && len(aliasMatches) == 0 && len(matchedRules) == 0 {
 		return nil
 	}
 
@@ -96,14 +130,19 @@ func (f file) linesIfMatch(projKey, flagKey, line string, aliases []string, matc
 	for _, alias := range aliasMatches {
 		ret.Aliases = []string{alias}
 	}
+	ret.MatchedRules = matchedRules
+
+	if blame != nil {
+		blame.annotate(&ret, f.path, matchLineNum)
+	}
 
 	return &ret
 }
 
-func (f file) toHunks(projKey string, aliases map[string][]string, ctxLines int, delimiters string) *ld.ReferenceHunksRep {
+func (f file) toHunks(projKey string, aliases map[string][]string, ctxLines int, delimiters string, blame *blameSource, rules map[string][]config.CompiledRule) *ld.ReferenceHunksRep {
 	hunks := []ld.HunkRep{}
 	for flagKey, flagAliases := range aliases {
-		hunks = append(hunks, f.aggregateHunksForFlag(projKey, flagKey, flagAliases, ctxLines, delimiters)...)
+		hunks = append(hunks, f.aggregateHunksForFlag(projKey, flagKey, flagAliases, ctxLines, delimiters, blame, rules[flagKey])...)
 	}
 	if len(hunks) == 0 {
 		return nil
@@ -112,10 +151,10 @@ func (f file) toHunks(projKey string, aliases map[string][]string, ctxLines int,
 }
 
 // aggregateHunksForFlag finds all references in a file, and combines matches into hunks if their context lines overlap
-func (f file) aggregateHunksForFlag(projKey, flagKey string, flagAliases []string, ctxLines int, delimiters string) []ld.HunkRep {
+func (f file) aggregateHunksForFlag(projKey, flagKey string, flagAliases []string, ctxLines int, delimiters string, blame *blameSource, rules []config.CompiledRule) []ld.HunkRep {
 	hunksForFlag := []ld.HunkRep{}
 	for i, line := range f.lines {
-		match := f.linesIfMatch(projKey, flagKey, line, flagAliases, i, ctxLines, delimiters)
+		match := f.linesIfMatch(projKey, flagKey, line, flagAliases, i, ctxLines, delimiters, blame, rules)
 		if match != nil {
 			lastHunkIdx := len(hunksForFlag) - 1
 			// If the previous hunk overlaps or is adjacent to the current hunk, merge them together
@@ -156,18 +195,24 @@ func mergeHunks(a, b ld.HunkRep, ctxLines int) []ld.HunkRep {
 			ProjKey:            a.ProjKey,
 			FlagKey:            a.FlagKey,
 			Aliases:            helpers.Dedupe(append(a.Aliases, b.Aliases...)),
+			MatchedRules:       helpers.Dedupe(append(a.MatchedRules, b.MatchedRules...)),
+			CommitHash:         a.CommitHash,
+			CommitAuthorEmail:  a.CommitAuthorEmail,
+			CommitTime:         a.CommitTime,
 		},
 	}
 }
 
 // processFiles starts goroutines to process files individually. When all files have completed processing, the references channel is closed to signal completion.
-func processFiles(files chan file, references chan ld.ReferenceHunksRep, projKey string, aliases map[string][]string, ctxLines int, delimiters string) {
+// blame is nil unless blame attribution was requested for this scan; each goroutine looks up its own
+// file's blame independently, so per-file blames are computed in parallel alongside matching.
+func processFiles(files chan file, references chan ld.ReferenceHunksRep, projKey string, aliases map[string][]string, ctxLines int, delimiters string, blame *blameSource, rules map[string][]config.CompiledRule) {
 	w := new(sync.WaitGroup)
 	for file := range files {
 		file := file
 		w.Add(1)
 		go func() {
-			reference := file.toHunks(projKey, aliases, ctxLines, delimiters)
+			reference := file.toHunks(projKey, aliases, ctxLines, delimiters, blame, rules)
 			if reference != nil {
 				references <- *reference
 			}
@@ -178,30 +223,223 @@ func processFiles(files chan file, references chan ld.ReferenceHunksRep, projKey
 	close(references)
 }
 
-func SearchForRefs(projKey, workspace string, searchTerms []string, aliases map[string][]string, ctxLines int, delimiters string) ([]ld.ReferenceHunksRep, error) {
+// readFiles walks workspace and sends every file that should be scanned to files, closing files once the
+// walk completes. A path is skipped (and, if it's a directory, never descended into) when it matches
+// cfg's skip patterns or ignoreMatcher's .gitignore/.ldignore patterns; a file that's read is further
+// skipped if it looks minified, per options.MinifiedLineLengthThreshold.
+func readFiles(files chan file, workspace string, cfg *config.Config, ignoreMatcher *ignore.Matcher) {
+	defer close(files)
+
+	threshold := options.MinifiedLineLengthThreshold()
+	_ = filepath.Walk(workspace, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(workspace, path)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		if rel == ".git" {
+			return filepath.SkipDir
+		}
+
+		if cfg.ShouldSkipPath(rel) || ignoreMatcher.Match(rel, info.IsDir()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		lines := strings.Split(string(contents), "\n")
+		if IsLikelyMinified(lines, threshold) {
+			return nil
+		}
+
+		files <- file{path: rel, lines: lines}
+		return nil
+	})
+}
+
+// hunkWindow reconstructs a contiguous window of file lines from a hunk, keeping its context lines plus
+// whichever of its added/removed lines matches keep. It returns the window along with the line number of
+// its first line in the revision keep's lines belong to (the post-image for Added, the pre-image for
+// Removed), so that a match found in the window can be mapped back to a real line number.
+func hunkWindow(h diff.Hunk, keep diff.LineType) ([]string, int) {
+	lines := make([]string, 0, len(h.Lines))
+	firstLineNum := 0
+	found := false
+	for _, l := range h.Lines {
+		if l.Type != diff.Context && l.Type != keep {
+			continue
+		}
+		// Context lines carry post-image numbers, so the window's first number is derived from the first
+		// line of keep's own type and its position in the window.
+		if l.Type == keep && !found {
+			firstLineNum = l.Number - len(lines)
+			found = true
+		}
+		lines = append(lines, l.Text)
+	}
+	return lines, firstLineNum
+}
+
+// RulesForFlags compiles cfg's custom patterns for every flag key in aliases, so that both SearchForRefs
+// and SearchForRefsInDiff can build the same per-flag rule set from the same loaded config.
+func RulesForFlags(cfg *config.Config, aliases map[string][]string) (map[string][]config.CompiledRule, error) {
+	rules := make(map[string][]config.CompiledRule, len(aliases))
+	for flagKey := range aliases {
+		flagRules, err := cfg.RulesForFlag(flagKey)
+		if err != nil {
+			return nil, err
+		}
+		rules[flagKey] = flagRules
+	}
+	return rules, nil
+}
+
+// SearchForRefsInDiff scans only the added and removed lines of a unified diff for flag key references,
+// rather than walking the full workspace. rules are the same per-flag config.CompiledRule set
+// SearchForRefs uses, so a team's custom coderefs.yaml patterns are honored in diff mode too, not just
+// full scans. headLines is used to fetch the current, post-image contents of a file so that added
+// references get full context from HEAD; when it reports that a file isn't available (e.g. it was
+// deleted, or the workspace isn't checked out), the diff's own context lines are used as a fallback. The
+// returned ReferenceDeltaRep distinguishes references added by the diff from references it removed, so
+// callers can report what a pull request introduces or deletes.
+func SearchForRefsInDiff(projKey string, aliases map[string][]string, ctxLines int, delimiters string, fileDiffs []diff.FileDiff, headLines func(path string) ([]string, bool), rules map[string][]config.CompiledRule) ld.ReferenceDeltaRep {
+	var delta ld.ReferenceDeltaRep
+	for _, fd := range fileDiffs {
+		head, hasHead := headLines(fd.Path)
+		for _, hunk := range fd.Hunks {
+			addedWindow, addedFirst := hunkWindow(hunk, diff.Added)
+			removedWindow, removedFirst := hunkWindow(hunk, diff.Removed)
+			for _, l := range hunk.Lines {
+				if l.Type == diff.Context {
+					continue
+				}
+
+				var f file
+				var matchLineNum, lineNumOffset int
+				switch {
+				case l.Type == diff.Added && hasHead:
+					f, matchLineNum = file{path: fd.Path, lines: head}, l.Number-1
+				case l.Type == diff.Added:
+					f, matchLineNum, lineNumOffset = file{path: fd.Path, lines: addedWindow}, l.Number-addedFirst, addedFirst-1
+				default:
+					f, matchLineNum, lineNumOffset = file{path: fd.Path, lines: removedWindow}, l.Number-removedFirst, removedFirst-1
+				}
+
+				for flagKey, flagAliases := range aliases {
+					match := f.linesIfMatch(projKey, flagKey, l.Text, flagAliases, matchLineNum, ctxLines, delimiters, nil, rules[flagKey])
+					if match == nil {
+						continue
+					}
+					match.StartingLineNumber += lineNumOffset
+					if l.Type == diff.Added {
+						delta.Added = append(delta.Added, *match)
+					} else {
+						delta.Removed = append(delta.Removed, *match)
+					}
+				}
+			}
+		}
+	}
+	return delta
+}
+
+// ScanStats summarizes a scan's output size once it has completed. It's only safe to read once the
+// ReferenceBatch channel returned by SearchForRefs has been fully drained.
+type ScanStats struct {
+	FileCount int
+	HunkCount int
+	// ByteCount is the total size, in bytes, of every hunk's Lines. It lets a caller report how much
+	// data a scan is about to upload, not just how many files and hunks it found.
+	ByteCount int64
+	// ExceededRecommendedLimits is true when the scan produced more files or hunks than
+	// recommendedMaxFileCount/recommendedMaxHunkCount suggest. Every reference is still streamed out
+	// regardless; this only tells the caller it may want to warn about scan size rather than silently
+	// proceeding, which is what happened back when these limits caused truncation.
+	ExceededRecommendedLimits bool
+}
+
+// SearchForRefs scans workspace for references to the flag keys of aliases and streams them as bounded-size batches
+// on the returned channel, rather than returning the full result set as a single in-memory slice (which
+// can grow into the hundreds of MB on large monorepos). The returned *ScanStats is populated as scanning
+// progresses and is safe to read once the channel has been drained.
+func SearchForRefs(projKey, workspace string, searchTerms []string, aliases map[string][]string, ctxLines int, delimiters string) (<-chan ld.ReferenceBatch, *ScanStats, error) {
+	var blame *blameSource
+	if options.WithBlame() {
+		var err error
+		blame, err = newBlameSource(workspace)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	cfg, err := config.Load(options.ConfigPath())
+	if err != nil {
+		return nil, nil, err
+	}
+	rules, err := RulesForFlags(cfg, aliases)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ignoreMatcher, err := ignore.NewMatcher(workspace)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	files := make(chan file)
 	references := make(chan ld.ReferenceHunksRep)
 
 	// Start workers to process files asynchronously as they are written to the files channel
-	go processFiles(files, references, projKey, aliases, ctxLines, delimiters)
+	go processFiles(files, references, projKey, aliases, ctxLines, delimiters, blame, rules)
+
+	go func() {
+		// readFiles is responsible for not enqueueing any path excluded by cfg's skip patterns,
+		// .gitignore/.ldignore, or the minified-file heuristic (options.MinifiedLineLengthThreshold).
+		readFiles(files, workspace, cfg, ignoreMatcher)
+	}()
 
-	// Blocks until all files have been read, but not necessarily processed
-	readFiles(files, workspace)
+	batches := make(chan ld.ReferenceBatch)
+	stats := &ScanStats{}
+	go func() {
+		defer close(batches)
 
-	ret := []ld.ReferenceHunksRep{}
-	totalHunks := 0
-	for reference := range references {
-		ret = append(ret, reference)
+		var batch ld.ReferenceBatch
+		batchHunkCount := 0
+		for reference := range references {
+			stats.FileCount++
+			stats.HunkCount += len(reference.Hunks)
+			for _, hunk := range reference.Hunks {
+				stats.ByteCount += int64(len(hunk.Lines))
+			}
 
-		// Reached maximum number of files with code references
-		if len(ret) >= maxFileCount {
-			return ret, nil
+			batch.References = append(batch.References, reference)
+			batchHunkCount += len(reference.Hunks)
+			if batchHunkCount >= batchHunkSize {
+				batches <- batch
+				batch = ld.ReferenceBatch{}
+				batchHunkCount = 0
+			}
 		}
-		totalHunks += len(reference.Hunks)
-		// Reached maximum number of hunks across all files
-		if totalHunks > maxHunkCount {
-			return ret, nil
+		if len(batch.References) > 0 {
+			batches <- batch
 		}
-	}
-	return ret, nil
+
+		stats.ExceededRecommendedLimits = stats.FileCount > recommendedMaxFileCount || stats.HunkCount > recommendedMaxHunkCount
+	}()
+
+	return batches, stats, nil
 }
@@ -0,0 +1,93 @@
+package search
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIsLikelyMinified(t *testing.T) {
+	tests := []struct {
+		name      string
+		lines     []string
+		threshold int
+		want      bool
+	}{
+		{"disabled threshold", []string{"aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"}, 0, false},
+		{"no lines", nil, 10, false},
+		{"short lines", []string{"short", "lines"}, 10, false},
+		{"long lines", []string{"aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"}, 10, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsLikelyMinified(tt.lines, tt.threshold); got != tt.want {
+				t.Errorf("IsLikelyMinified() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// setConfigPath points the package-level --config flag at path for the duration of the test.
+func setConfigPath(t *testing.T, path string) {
+	t.Helper()
+	configFlag := flag.Lookup("config")
+	prev := configFlag.Value.String()
+	if err := configFlag.Value.Set(path); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = configFlag.Value.Set(prev)
+	})
+}
+
+func TestSearchForRefsSkipsIgnoredAndMinifiedFiles(t *testing.T) {
+	workspace := t.TempDir()
+
+	write := func(rel, contents string) {
+		path := filepath.Join(workspace, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("match.go", `flag := "my-flag"`)
+	write(".gitignore", "vendor/\n")
+	write("vendor/match.go", `flag := "my-flag"`)
+	write("skipped.go", `flag := "my-flag"`)
+	write("bundle.js", `var f="my-flag";`+strings.Repeat("x", 1000))
+	thresholdFlag := flag.Lookup("minifiedLineLengthThreshold")
+	prevThreshold := thresholdFlag.Value.String()
+	if err := thresholdFlag.Value.Set("200"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = thresholdFlag.Value.Set(prevThreshold)
+	})
+	configPath := filepath.Join(workspace, "coderefs.yaml")
+	write("coderefs.yaml", "skipPatterns:\n  - \"skipped.go\"\n")
+	setConfigPath(t, configPath)
+
+	batches, stats, err := SearchForRefs("proj", workspace, []string{"my-flag"}, map[string][]string{"my-flag": nil}, 0, "\"")
+	if err != nil {
+		t.Fatalf("SearchForRefs returned error: %s", err)
+	}
+
+	var paths []string
+	for batch := range batches {
+		for _, ref := range batch.References {
+			paths = append(paths, ref.Path)
+		}
+	}
+
+	if len(paths) != 1 || paths[0] != "match.go" {
+		t.Fatalf("expected only match.go to be scanned, got %v", paths)
+	}
+	if stats.FileCount != 1 {
+		t.Fatalf("expected FileCount 1, got %d", stats.FileCount)
+	}
+}
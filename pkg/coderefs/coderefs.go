@@ -0,0 +1,104 @@
+// Package coderefs is the program's single entry point, called once main.go has finished populating
+// options from the detected host environment.
+package coderefs
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/launchdarkly/ld-find-code-refs/internal/config"
+	"github.com/launchdarkly/ld-find-code-refs/internal/diff"
+	"github.com/launchdarkly/ld-find-code-refs/internal/ld"
+	"github.com/launchdarkly/ld-find-code-refs/internal/log"
+	"github.com/launchdarkly/ld-find-code-refs/internal/options"
+	"github.com/launchdarkly/ld-find-code-refs/internal/search"
+)
+
+const userAgent = "ld-find-code-refs"
+
+// Scan runs a single scan using the options configured for this run. In diff mode (options.DiffMode) it
+// reads a unified diff from stdin and prints the flag references it adds and removes, for a caller to
+// post as a pull request comment, instead of scanning and uploading the full workspace.
+func Scan() {
+	client := ld.NewClient(options.AccessToken(), options.BaseUri(), userAgent)
+
+	flagKeys, err := client.GetFlagKeys(options.ProjKey())
+	if err != nil {
+		log.Error.Fatalf("error fetching flag keys: %s", err)
+	}
+	aliases := make(map[string][]string, len(flagKeys))
+	for _, key := range flagKeys {
+		aliases[key] = nil
+	}
+
+	if options.DiffMode() {
+		scanDiff(flagKeys, aliases)
+		return
+	}
+	scanWorkspace(client, flagKeys, aliases)
+}
+
+// scanDiff scans a unified diff read from stdin and prints the references it adds and removes as JSON,
+// honoring the same coderefs.yaml custom patterns a full workspace scan would.
+func scanDiff(flagKeys []string, aliases map[string][]string) {
+	fileDiffs, err := diff.Parse(os.Stdin)
+	if err != nil {
+		log.Error.Fatalf("error parsing diff: %s", err)
+	}
+
+	cfg, err := config.Load(options.ConfigPath())
+	if err != nil {
+		log.Error.Fatalf("error loading config: %s", err)
+	}
+	rules, err := search.RulesForFlags(cfg, aliases)
+	if err != nil {
+		log.Error.Fatalf("error compiling config rules: %s", err)
+	}
+
+	delta := search.SearchForRefsInDiff(options.ProjKey(), aliases, options.CtxLines(), options.Delimiters(), fileDiffs, headLines, rules)
+	if err := json.NewEncoder(os.Stdout).Encode(delta); err != nil {
+		log.Error.Fatalf("error encoding reference delta: %s", err)
+	}
+}
+
+// headLines returns the current, post-image contents of path (relative to the scanned workspace), for
+// SearchForRefsInDiff to use as context for added references. It reports false when the file can't be
+// read, e.g. it was deleted by the diff.
+func headLines(path string) ([]string, bool) {
+	contents, err := ioutil.ReadFile(filepath.Join(options.Dir(), path))
+	if err != nil {
+		return nil, false
+	}
+	return strings.Split(string(contents), "\n"), true
+}
+
+// scanWorkspace scans the full workspace and uploads the resulting reference set to LaunchDarkly in
+// sequenced chunks.
+func scanWorkspace(client *ld.Client, flagKeys []string, aliases map[string][]string) {
+	batchChan, stats, err := search.SearchForRefs(options.ProjKey(), options.Dir(), flagKeys, aliases, options.CtxLines(), options.Delimiters())
+	if err != nil {
+		log.Error.Fatalf("error scanning workspace: %s", err)
+	}
+
+	// Each batch is uploaded as soon as it comes off batchChan, so the full reference set is never held
+	// in memory at once; only the total chunk count, needed by PutCodeReferenceBranchChunksComplete, is
+	// tracked here.
+	seq := 0
+	for batch := range batchChan {
+		if err := client.PutCodeReferenceBranchChunk(options.RepoName(), options.Branch(), batch, seq); err != nil {
+			log.Error.Fatalf("error uploading code reference chunk %d: %s", seq, err)
+		}
+		seq++
+	}
+	if err := client.PutCodeReferenceBranchChunksComplete(options.RepoName(), options.Branch(), seq); err != nil {
+		log.Error.Fatalf("error completing code reference upload: %s", err)
+	}
+
+	if stats.ExceededRecommendedLimits {
+		log.Error.Printf("scan found %d files and %d hunks, exceeding the recommended limits; results were still uploaded in full", stats.FileCount, stats.HunkCount)
+	}
+	log.Info.Printf("uploaded %d files, %d hunks, %d bytes across %d chunks", stats.FileCount, stats.HunkCount, stats.ByteCount, seq)
+}
@@ -0,0 +1,25 @@
+package hostenv
+
+import (
+	"fmt"
+	"os"
+)
+
+// BitbucketPipelines detects and configures a scan running as a Bitbucket Pipelines job.
+type BitbucketPipelines struct{}
+
+func (BitbucketPipelines) Name() string { return "Bitbucket Pipelines" }
+
+func (BitbucketPipelines) Detect() bool {
+	return os.Getenv("BITBUCKET_BUILD_NUMBER") != ""
+}
+
+func (BitbucketPipelines) Populate(scanOptions map[string]string) error {
+	scanOptions["branch"] = os.Getenv("BITBUCKET_BRANCH")
+	scanOptions["repoType"] = "bitbucket"
+	scanOptions["repoName"] = os.Getenv("BITBUCKET_REPO_SLUG")
+	scanOptions["dir"] = os.Getenv("BITBUCKET_CLONE_DIR")
+	scanOptions["repoUrl"] = fmt.Sprintf("https://bitbucket.org/%s", os.Getenv("BITBUCKET_REPO_FULL_NAME"))
+	scanOptions["commitSha"] = os.Getenv("BITBUCKET_COMMIT")
+	return nil
+}
@@ -0,0 +1,23 @@
+package hostenv
+
+import "github.com/launchdarkly/ld-find-code-refs/internal/options"
+
+// GenericGit configures a scan for self-hosted git or any CI provider without dedicated support, using
+// explicit --repoUrl, --branch, and --commitSha flags in place of an auto-detected CI environment. It's
+// checked last, after every provider with an environment to auto-detect.
+type GenericGit struct{}
+
+func (GenericGit) Name() string { return "self-hosted git" }
+
+func (GenericGit) Detect() bool {
+	return options.RepoUrl() != "" && options.Branch() != ""
+}
+
+func (GenericGit) Populate(scanOptions map[string]string) error {
+	scanOptions["branch"] = options.Branch()
+	scanOptions["repoType"] = "custom"
+	scanOptions["repoUrl"] = options.RepoUrl()
+	scanOptions["dir"] = options.Dir()
+	scanOptions["commitSha"] = options.CommitSha()
+	return nil
+}
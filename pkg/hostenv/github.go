@@ -0,0 +1,85 @@
+package hostenv
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// GitHubActions detects and configures a scan running as a GitHub Actions workflow.
+type GitHubActions struct{}
+
+func (GitHubActions) Name() string { return "GitHub Actions" }
+
+func (GitHubActions) Detect() bool {
+	return os.Getenv("GITHUB_ACTIONS") == "true"
+}
+
+func (GitHubActions) Populate(scanOptions map[string]string) error {
+	ghRepo := strings.Split(os.Getenv("GITHUB_REPOSITORY"), "/")
+	if len(ghRepo) < 2 {
+		return fmt.Errorf("unable to validate GitHub repository name: %s", ghRepo)
+	}
+
+	branch, err := parseGitHubBranch(os.Getenv("GITHUB_REF"))
+	if err != nil {
+		return fmt.Errorf("error parsing GITHUB_REF: %w", err)
+	}
+
+	event, err := parseGitHubEvent(os.Getenv("GITHUB_EVENT_PATH"))
+	if err != nil {
+		return fmt.Errorf("error parsing GitHub event payload at %s: %w", os.Getenv("GITHUB_EVENT_PATH"), err)
+	}
+
+	scanOptions["branch"] = branch
+	scanOptions["repoType"] = "github"
+	scanOptions["repoName"] = ghRepo[1]
+	scanOptions["dir"] = os.Getenv("GITHUB_WORKSPACE")
+	scanOptions["updateSequenceId"] = strconv.FormatInt(event.Repo.PushedAt*1000, 10) // seconds to milliseconds
+	scanOptions["repoUrl"] = event.Repo.Url
+	if scanOptions["defaultBranch"] == "" {
+		scanOptions["defaultBranch"] = event.Repo.DefaultBranch
+	}
+	return nil
+}
+
+type githubEvent struct {
+	Repo struct {
+		Url           string `json:"html_url"`
+		DefaultBranch string `json:"default_branch"`
+		PushedAt      int64  `json:"pushed_at"`
+	} `json:"repository"`
+}
+
+func parseGitHubEvent(path string) (*githubEvent, error) {
+	/* #nosec */
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	b, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	var evt githubEvent
+	if err := json.Unmarshal(b, &evt); err != nil {
+		return nil, err
+	}
+	return &evt, nil
+}
+
+var githubBranchPattern = regexp.MustCompile(`^refs/heads/(.+)$`)
+
+func parseGitHubBranch(ref string) (string, error) {
+	m := githubBranchPattern.FindStringSubmatch(ref)
+	if m == nil {
+		return "", fmt.Errorf("expected branch name starting with refs/heads/, got: %s", ref)
+	}
+	return m[1], nil
+}
@@ -0,0 +1,24 @@
+package hostenv
+
+import "os"
+
+// GitLabCI detects and configures a scan running as a GitLab CI job.
+type GitLabCI struct{}
+
+func (GitLabCI) Name() string { return "GitLab CI" }
+
+func (GitLabCI) Detect() bool {
+	return os.Getenv("GITLAB_CI") == "true"
+}
+
+func (GitLabCI) Populate(scanOptions map[string]string) error {
+	// Unlike GitHub's refs/heads/<branch>, GitLab already exposes the bare branch name.
+	scanOptions["branch"] = os.Getenv("CI_COMMIT_REF_NAME")
+	scanOptions["repoType"] = "gitlab"
+	scanOptions["repoName"] = os.Getenv("CI_PROJECT_PATH")
+	scanOptions["dir"] = os.Getenv("CI_PROJECT_DIR")
+	scanOptions["repoUrl"] = os.Getenv("CI_PROJECT_URL")
+	scanOptions["defaultBranch"] = os.Getenv("CI_DEFAULT_BRANCH")
+	scanOptions["commitSha"] = os.Getenv("CI_COMMIT_SHA")
+	return nil
+}
@@ -0,0 +1,92 @@
+// Package hostenv detects which CI provider (or generic self-hosted git) a scan is running under, and
+// populates the scan options that provider can derive from its environment.
+package hostenv
+
+import (
+	"fmt"
+	"strconv"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// Provider detects whether the program is running in a particular CI environment and populates the scan
+// options it can derive from that environment.
+type Provider interface {
+	// Name identifies the provider for logging.
+	Name() string
+	// Detect reports whether the current environment matches this provider.
+	Detect() bool
+	// Populate fills scanOptions with the values this provider can derive from its environment.
+	Populate(scanOptions map[string]string) error
+}
+
+// providers is the list of supported providers, in the order they're checked. GenericGit is last since
+// it's the catch-all for self-hosted git, used only when none of the CI-specific environments match.
+var providers = []Provider{
+	GitHubActions{},
+	GitLabCI{},
+	BitbucketPipelines{},
+	GenericGit{},
+}
+
+// Detect returns the first provider whose environment is detected, or nil if none match.
+func Detect() Provider {
+	for _, p := range providers {
+		if p.Detect() {
+			return p
+		}
+	}
+	return nil
+}
+
+// Populate detects the current CI provider and returns the scan options it derives from its
+// environment (along with the provider's name, for logging), filling in updateSequenceId from the
+// repository's commit history when the provider didn't already derive one from its own push event.
+func Populate() (map[string]string, string, error) {
+	provider := Detect()
+	if provider == nil {
+		return nil, "", fmt.Errorf("unable to detect a supported CI provider; pass --repoUrl, --branch, and --commitSha explicitly for self-hosted git")
+	}
+
+	scanOptions := map[string]string{}
+	if err := provider.Populate(scanOptions); err != nil {
+		return nil, "", fmt.Errorf("%s: %w", provider.Name(), err)
+	}
+
+	if err := resolveUpdateSequenceId(scanOptions); err != nil {
+		return nil, "", fmt.Errorf("%s: %w", provider.Name(), err)
+	}
+
+	return scanOptions, provider.Name(), nil
+}
+
+// resolveUpdateSequenceId fills scanOptions["updateSequenceId"] from the scanned commit's committer
+// time, converted to milliseconds, unless the provider already derived one from its own push event.
+func resolveUpdateSequenceId(scanOptions map[string]string) error {
+	if scanOptions["updateSequenceId"] != "" {
+		return nil
+	}
+
+	repo, err := git.PlainOpen(scanOptions["dir"])
+	if err != nil {
+		return err
+	}
+
+	hash := plumbing.NewHash(scanOptions["commitSha"])
+	if hash.IsZero() {
+		head, err := repo.Head()
+		if err != nil {
+			return err
+		}
+		hash = head.Hash()
+	}
+
+	commit, err := repo.CommitObject(hash)
+	if err != nil {
+		return err
+	}
+
+	scanOptions["updateSequenceId"] = strconv.FormatInt(commit.Committer.When.Unix()*1000, 10)
+	return nil
+}
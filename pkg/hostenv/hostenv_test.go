@@ -0,0 +1,77 @@
+package hostenv
+
+import (
+	"flag"
+	"testing"
+)
+
+// stubProvider is a minimal Provider for exercising Detect's provider-ordering logic without depending
+// on any real CI environment variables.
+type stubProvider struct {
+	name    string
+	detects bool
+}
+
+func (s stubProvider) Name() string { return s.name }
+func (s stubProvider) Detect() bool { return s.detects }
+func (s stubProvider) Populate(scanOptions map[string]string) error {
+	scanOptions["repoType"] = s.name
+	return nil
+}
+
+func TestDetectReturnsFirstMatch(t *testing.T) {
+	orig := providers
+	defer func() { providers = orig }()
+
+	providers = []Provider{
+		stubProvider{name: "first", detects: false},
+		stubProvider{name: "second", detects: true},
+		stubProvider{name: "third", detects: true},
+	}
+
+	p := Detect()
+	if p == nil || p.Name() != "second" {
+		t.Fatalf("expected the first matching provider (second), got %v", p)
+	}
+}
+
+func TestDetectReturnsNilWhenNoneMatch(t *testing.T) {
+	orig := providers
+	defer func() { providers = orig }()
+
+	providers = []Provider{stubProvider{name: "first", detects: false}}
+
+	if p := Detect(); p != nil {
+		t.Fatalf("expected no provider to match, got %v", p)
+	}
+}
+
+func TestGenericGitDetect(t *testing.T) {
+	tests := []struct {
+		name      string
+		repoUrl   string
+		branch    string
+		wantMatch bool
+	}{
+		{"both set", "https://example.com/repo.git", "main", true},
+		{"missing branch", "https://example.com/repo.git", "", false},
+		{"missing repoUrl", "", "main", false},
+		{"neither set", "", "", false},
+	}
+	repoUrlFlag, branchFlag := flag.Lookup("repoUrl"), flag.Lookup("branch")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prevUrl, prevBranch := repoUrlFlag.Value.String(), branchFlag.Value.String()
+			defer func() {
+				_ = repoUrlFlag.Value.Set(prevUrl)
+				_ = branchFlag.Value.Set(prevBranch)
+			}()
+			_ = repoUrlFlag.Value.Set(tt.repoUrl)
+			_ = branchFlag.Value.Set(tt.branch)
+
+			if got := (GenericGit{}).Detect(); got != tt.wantMatch {
+				t.Errorf("Detect() = %v, want %v", got, tt.wantMatch)
+			}
+		})
+	}
+}